@@ -0,0 +1,32 @@
+/*
+ * Copyright 2019 Google LLC.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+// Config is the structure produced by Load, LoadReader, and Merge once a
+// config file and its imports have been resolved and merged.
+type Config struct {
+	// Provenance maps a dotted field path (e.g. "projects.id") to the file(s)
+	// that set it, in the order they were merged. It is populated by the
+	// loader itself, not read from config files, so it is never marshaled
+	// back out.
+	Provenance map[string][]string `json:"-"`
+}
+
+// Init validates c after it has been fully loaded and merged.
+func (c *Config) Init() error {
+	return nil
+}
@@ -20,14 +20,17 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"text/template"
 
 	"github.com/ghodss/yaml"
-	"github.com/imdario/mergo"
 	"github.com/mitchellh/go-homedir"
 )
 
@@ -53,17 +56,131 @@ func NormalizePath(path string) (string, error) {
 	return filepath.Abs(filepath.Join(cwd, path))
 }
 
+// LoadOption configures optional behavior of Load.
+type LoadOption func(*loadOptions)
+
+type loadOptions struct {
+	appendLocalSlices bool
+	mergeStrategy     MergeStrategy
+}
+
+// WithLocalOverrideAppendSlice makes `.local` override files append to base
+// slices instead of replacing them wholesale.
+func WithLocalOverrideAppendSlice() LoadOption {
+	return func(o *loadOptions) {
+		o.appendLocalSlices = true
+	}
+}
+
+// MergeStrategy controls how slices are combined when an imported config is
+// merged into the map that imported it.
+type MergeStrategy string
+
+const (
+	// MergeAppend appends the imported slice to the end of the base slice.
+	// This is the default, preserving the loader's historical behavior.
+	MergeAppend MergeStrategy = "append"
+	// MergeReplace replaces the base slice with the imported slice wholesale.
+	MergeReplace MergeStrategy = "replace"
+	// MergePrepend prepends the imported slice to the front of the base slice.
+	MergePrepend MergeStrategy = "prepend"
+	// MergeUniqueByKey merges slices of maps, matching elements by a
+	// designated key field: matching elements are deep-merged in place,
+	// and unmatched elements are appended, similar to a Kustomize
+	// strategic merge patch.
+	MergeUniqueByKey MergeStrategy = "unique-by-key"
+)
+
+// WithMergeStrategy sets the default MergeStrategy used to combine slices
+// when merging imported configs. It can be overridden per-import via the
+// `strategy` field on an imports entry.
+func WithMergeStrategy(s MergeStrategy) LoadOption {
+	return func(o *loadOptions) {
+		o.mergeStrategy = s
+	}
+}
+
 // Load loads a config from the given path.
-func Load(path string) (*Config, error) {
+func Load(path string, opts ...LoadOption) (*Config, error) {
 	path, err := NormalizePath(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to normalize path %q: %v", path, err)
 	}
-	m, err := loadMap(path, nil)
+	o := new(loadOptions)
+	for _, opt := range opts {
+		opt(o)
+	}
+	st := newLoadState(o)
+	m, err := loadMap(path, nil, st)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config to map: %v", err)
+	}
+	return buildConfig(m, st)
+}
+
+// LoadReader loads a config from r instead of a file on disk, allowing
+// callers to compose configs from sources such as an HTTP response, an
+// in-memory template, or a secret manager payload. Relative `imports.path`
+// and `imports.pattern` entries are resolved against baseDir, just as they
+// would be against the directory of a file loaded with Load.
+func LoadReader(r io.Reader, baseDir string, opts ...LoadOption) (*Config, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %v", err)
+	}
+	o := new(loadOptions)
+	for _, opt := range opts {
+		opt(o)
+	}
+	st := newLoadState(o)
+	m, err := loadMapFromBytes(b, "", baseDir, nil, st)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load config to map: %v", err)
 	}
+	return buildConfig(m, st)
+}
 
+// Merge reads a config from r and deep-merges it on top of c. Unlike an
+// `imports` entry, values from r win over c's on conflict; slices are
+// combined with MergeAppend, r's elements first. c is re-initialized
+// via Init afterwards. Merge is safe to call repeatedly, so callers can layer
+// configs, e.g. defaults, then an env overlay, then a runtime overlay.
+func (c *Config) Merge(r io.Reader) error {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %v", err)
+	}
+	existing, err := configToMap(c)
+	if err != nil {
+		return fmt.Errorf("failed to marshal existing config to map: %v", err)
+	}
+	st := newLoadState(new(loadOptions))
+	// Seed provenance with what Load (or a prior Merge) already recorded on
+	// c, so this call only adds to it instead of wiping out history for
+	// fields it never touches.
+	for k, v := range c.Provenance {
+		st.provenance[k] = append([]string(nil), v...)
+	}
+	incoming, err := loadMapFromBytes(b, "", "", nil, st)
+	if err != nil {
+		return fmt.Errorf("failed to load config to merge: %v", err)
+	}
+	strategy, key := strategyFor(nil, st.opts)
+	// incoming is merged as dst so its values win over existing on conflict,
+	// matching the "later layer overrides earlier ones" semantics promised
+	// above; mergeMaps has dst take precedence over src.
+	merged, err := buildConfig(mergeMaps(incoming, existing, strategy, key), st)
+	if err != nil {
+		return err
+	}
+	*c = *merged
+	return nil
+}
+
+// buildConfig marshals m back to JSON, unmarshals it into a Config,
+// initializes it, and (when st is non-nil) attaches the import provenance
+// collected while loading m, mirroring the final steps of Load.
+func buildConfig(m map[string]interface{}, st *loadState) (*Config, error) {
 	b, err := json.MarshalIndent(m, "", "  ")
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal config map: %v", err)
@@ -75,26 +192,279 @@ func Load(path string) (*Config, error) {
 	}
 
 	if err := conf.Init(); err != nil {
-		return nil, fmt.Errorf("failed to initialize config: %v", err)
+		err = fmt.Errorf("failed to initialize config: %v", err)
+		if st != nil {
+			err = correlateProvenance(err, st.provenance)
+		}
+		return nil, err
+	}
+	if st != nil {
+		conf.Provenance = st.provenance
 	}
 	return conf, nil
 }
 
+// configToMap marshals c to its map[string]interface{} representation, the
+// inverse of buildConfig, so it can be deep-merged with another map.
+func configToMap(c *Config) (map[string]interface{}, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %v", err)
+	}
+	m := make(map[string]interface{})
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config to map: %v", err)
+	}
+	return m, nil
+}
+
 type importsItem struct {
 	Path string                 `json:"path"`
 	Data map[string]interface{} `json:"data"`
 
 	Pattern string `json:"pattern"`
+
+	// Strategy overrides the loader's default MergeStrategy for this
+	// import only. If empty, the default passed to Load via
+	// WithMergeStrategy (or MergeAppend) is used.
+	Strategy MergeStrategy `json:"strategy"`
+	// Key names the field used to match slice elements together when
+	// Strategy is MergeUniqueByKey.
+	Key string `json:"key"`
+
+	// Recursive, when true, walks the directory tree rooted at Path (or at
+	// the portion of Pattern before a "**" doublestar segment) collecting
+	// every *.yaml/*.yml/*.json file, instead of treating Path as a single
+	// file. Matches are merged in lexical order. This lets large configs be
+	// split into per-resource files under a conf.d-style folder instead of
+	// listing each one explicitly.
+	Recursive bool `json:"recursive"`
+}
+
+// loadState threads the options, cycle-detection chain, load cache, and
+// provenance map through a single Load/LoadReader/Merge call's recursion.
+type loadState struct {
+	opts *loadOptions
+
+	// chain holds the absolute paths of files currently being loaded, from
+	// the root down to the file being processed right now, used to detect
+	// import cycles.
+	chain []string
+
+	// cache holds the fully-resolved map for each absolute path already
+	// loaded, so a diamond import (A imports B and C, both of which import
+	// D) loads and merges D exactly once instead of once per path to it.
+	cache map[string]map[string]interface{}
+
+	// provenance maps a dotted key path (e.g. "projects.id") to the file(s)
+	// whose contents set it, in the order they were merged.
+	provenance map[string][]string
+}
+
+func newLoadState(opts *loadOptions) *loadState {
+	return &loadState{
+		opts:       opts,
+		cache:      make(map[string]map[string]interface{}),
+		provenance: make(map[string][]string),
+	}
+}
+
+// ImportError reports a problem loading or parsing a single config file,
+// with file/line context so a large multi-file import tree is debuggable.
+// Line is 0 when no position could be determined.
+type ImportError struct {
+	Path string
+	Line int
+	Err  error
+}
+
+func (e *ImportError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %v", e.Path, e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e *ImportError) Unwrap() error { return e.Err }
+
+// yamlLineRE matches the "line N" position ghodss/yaml (via yaml.v2) embeds
+// in its error messages, e.g. "yaml: line 3: did not find expected key".
+var yamlLineRE = regexp.MustCompile(`line (\d+)`)
+
+// newImportError wraps err as an *ImportError for path, extracting a line
+// number from err's message (yaml.v2-style) or, for a JSON syntax error, by
+// counting newlines in raw up to the error's byte offset.
+func newImportError(path string, raw []byte, err error) *ImportError {
+	ie := &ImportError{Path: path, Err: err}
+	if m := yamlLineRE.FindStringSubmatch(err.Error()); m != nil {
+		if n, convErr := strconv.Atoi(m[1]); convErr == nil {
+			ie.Line = n
+		}
+	} else if se, ok := err.(*json.SyntaxError); ok {
+		ie.Line = lineForOffset(raw, se.Offset)
+	}
+	return ie
+}
+
+// lineForOffset returns the 1-based line number containing byte offset in b.
+func lineForOffset(b []byte, offset int64) int {
+	if offset <= 0 || offset > int64(len(b)) {
+		return 0
+	}
+	return bytes.Count(b[:offset], []byte("\n")) + 1
+}
+
+// MultiError aggregates errors collected while loading an entire import
+// tree, instead of aborting on the first one.
+type MultiError struct {
+	Errs []error
+}
+
+func (m *MultiError) Error() string {
+	if len(m.Errs) == 1 {
+		return m.Errs[0].Error()
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d errors occurred:\n", len(m.Errs))
+	for _, err := range m.Errs {
+		fmt.Fprintf(&sb, "  * %v\n", err)
+	}
+	return sb.String()
+}
+
+// add appends err to m, flattening err if it is itself a *MultiError.
+func (m *MultiError) add(err error) {
+	if err == nil {
+		return
+	}
+	if me, ok := err.(*MultiError); ok {
+		m.Errs = append(m.Errs, me.Errs...)
+		return
+	}
+	m.Errs = append(m.Errs, err)
+}
+
+// errOrNil returns m if it holds any errors, nil otherwise.
+func (m *MultiError) errOrNil() error {
+	if len(m.Errs) == 0 {
+		return nil
+	}
+	return m
+}
+
+// correlateProvenance annotates err with the file(s) recorded in provenance
+// as having set the field named in err's message, if one can be found, so
+// a rejected field can be traced back to the import that set it.
+func correlateProvenance(err error, provenance map[string][]string) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+
+	keys := make([]string, 0, len(provenance))
+	for key := range provenance {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		// Match key as a whole word so a short field name like "id" doesn't
+		// false-positive inside unrelated words such as "invalid".
+		matched, err2 := regexp.MatchString(`\b`+regexp.QuoteMeta(key)+`\b`, msg)
+		if err2 != nil {
+			continue
+		}
+		if matched {
+			return fmt.Errorf("%v (set by %s)", err, strings.Join(provenance[key], ", "))
+		}
+	}
+	return err
+}
+
+// strategyFor returns the effective merge strategy and key for imp, falling
+// back to the loader-wide default when imp does not override it.
+func strategyFor(imp *importsItem, opts *loadOptions) (MergeStrategy, string) {
+	strategy := MergeAppend
+	if opts != nil && opts.mergeStrategy != "" {
+		strategy = opts.mergeStrategy
+	}
+	key := ""
+	if imp != nil {
+		if imp.Strategy != "" {
+			strategy = imp.Strategy
+		}
+		key = imp.Key
+	}
+	return strategy, key
 }
 
 // loadMap loads the config at path into a map. It will also merge all imported configs.
 // The given path should be absolute.
-func loadMap(path string, data map[string]interface{}) (map[string]interface{}, error) {
+func loadMap(path string, data map[string]interface{}, st *loadState) (map[string]interface{}, error) {
+	// Files imported with template data are instantiated differently per
+	// call site, so they cannot be cycle-checked or cached by path alone.
+	if len(data) == 0 {
+		for _, ancestor := range st.chain {
+			if ancestor == path {
+				return nil, fmt.Errorf("import cycle detected: %s -> %s", strings.Join(st.chain, " -> "), path)
+			}
+		}
+		if _, ok := st.cache[path]; ok {
+			// Already loaded and merged in via another import earlier in
+			// this call; merging it again would duplicate its slices.
+			return make(map[string]interface{}), nil
+		}
+	}
+
 	b, err := ioutil.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file at path %q: %v", path, err)
 	}
 
+	st.chain = append(st.chain, path)
+	m, err := loadMapFromBytes(b, path, filepath.Dir(path), data, st)
+	st.chain = st.chain[:len(st.chain)-1]
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		st.cache[path] = cloneMap(m)
+	}
+	return m, nil
+}
+
+// cloneMap returns a deep copy of m so that the copy kept in the cache is not
+// changed when the caller later merges other imports into m.
+func cloneMap(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = cloneValue(v)
+	}
+	return out
+}
+
+func cloneValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		return cloneMap(t)
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, e := range t {
+			out[i] = cloneValue(e)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// loadMapFromBytes loads the config in b into a map, also merging all
+// imported configs. path identifies the file b was read from and is used to
+// look for a sibling `.local` override (see applyLocalOverride); it should
+// be empty when b did not come from a file, e.g. via LoadReader or Merge.
+// dir is the directory relative `imports` entries are resolved against.
+func loadMapFromBytes(b []byte, path, dir string, data map[string]interface{}, st *loadState) (map[string]interface{}, error) {
 	if len(data) > 0 {
 		tmpl, err := template.New(path).Parse(string(b))
 		if err != nil {
@@ -109,12 +479,12 @@ func loadMap(path string, data map[string]interface{}) (map[string]interface{},
 
 	var raw json.RawMessage
 	if err := yaml.Unmarshal(b, &raw); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal config at path %q: %v", path, err)
+		return nil, newImportError(path, b, fmt.Errorf("failed to unmarshal config: %v", err))
 	}
 
 	root := make(map[string]interface{})
 	if err := json.Unmarshal(raw, &root); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal raw config to map at path %q: %v", path, err)
+		return nil, newImportError(path, raw, fmt.Errorf("failed to unmarshal raw config to map: %v", err))
 	}
 
 	type config struct {
@@ -122,14 +492,34 @@ func loadMap(path string, data map[string]interface{}) (map[string]interface{},
 	}
 	conf := new(config)
 	if err := json.Unmarshal(raw, conf); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal raw config to struct with imports at path %q: %v", path, err)
+		return nil, newImportError(path, raw, fmt.Errorf("failed to unmarshal raw config to struct with imports: %v", err))
+	}
+
+	// Always record provenance, even for content loaded via LoadReader/Merge
+	// (path == ""), so Merge doesn't silently drop attribution for the
+	// content it just merged in.
+	provenanceLabel := path
+	if provenanceLabel == "" {
+		provenanceLabel = "<reader>"
+	}
+	recordProvenance(st.provenance, "", root, provenanceLabel)
+
+	if path != "" {
+		if err := applyLocalOverride(path, root, st.opts, st.provenance); err != nil {
+			return nil, err
+		}
 	}
 
-	dir := filepath.Dir(path)
 	pathMap := map[string]bool{
 		path: true,
 	}
+	merr := new(MultiError)
 	for _, imp := range conf.Imports {
+		if imp.Recursive {
+			// Handled below, alongside pattern imports, so all matches are
+			// merged together in lexical order.
+			continue
+		}
 		impPath := imp.Path
 		if impPath == "" {
 			continue
@@ -139,73 +529,356 @@ func loadMap(path string, data map[string]interface{}) (map[string]interface{},
 		}
 		pathMap[impPath] = true
 
-		impMap, err := loadMap(impPath, imp.Data)
+		impMap, err := loadMap(impPath, imp.Data, st)
 		if err != nil {
-			return nil, fmt.Errorf("failed to load %q to map: %v", impPath, err)
-		}
-		if err := mergo.Merge(&root, impMap, mergo.WithAppendSlice); err != nil {
-			return nil, fmt.Errorf("failed to merge imported file %q: %v", impPath, err)
+			merr.add(err)
+			continue
 		}
+		strategy, key := strategyFor(imp, st.opts)
+		root = mergeMaps(root, impMap, strategy, key)
 	}
 
-	paths, err := patternPaths(path, conf.Imports)
+	matches, err := patternPaths(dir, path, conf.Imports)
 	if err != nil {
-		return nil, err
+		merr.add(err)
 	}
 
-	for _, p := range paths {
-		if pathMap[p] {
+	for _, pm := range matches {
+		if pathMap[pm.path] {
 			continue
 		}
-		impMap, err := loadMap(p, nil)
+		impMap, err := loadMap(pm.path, nil, st)
 		if err != nil {
-			return nil, fmt.Errorf("failed to load %q to map: %v", p, err)
-		}
-		if err := mergo.Merge(&root, impMap, mergo.WithAppendSlice); err != nil {
-			return nil, fmt.Errorf("failed to merge imported file %q: %v", p, err)
+			merr.add(err)
+			continue
 		}
+		strategy, key := strategyFor(pm.imp, st.opts)
+		root = mergeMaps(root, impMap, strategy, key)
+	}
+	if err := merr.errOrNil(); err != nil {
+		return nil, err
 	}
 	return root, nil
 }
 
-// patternPaths returns all files matching the patterns defined
-// in importsList.
+// recordProvenance walks m recursively, appending file to provenance[key] for
+// every leaf value found, where key is the dot-separated path to it (e.g.
+// "projects.id"). Slices are treated as leaves: it records that file set the
+// slice as a whole rather than descending into individual elements.
+func recordProvenance(provenance map[string][]string, prefix string, m map[string]interface{}, file string) {
+	for k, v := range m {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if nested, ok := v.(map[string]interface{}); ok {
+			recordProvenance(provenance, key, nested, file)
+			continue
+		}
+		provenance[key] = append(provenance[key], file)
+	}
+}
+
+// mergeMaps deep-merges src into dst and returns dst. Existing values in dst
+// win over src for scalars and maps, matching the loader's historical
+// "base file wins over imports" precedence; slices are combined according to
+// strategy. mergeMaps does not delegate to a third-party merge library so
+// that the interaction between nested maps, slices, and slices-of-maps stays
+// deterministic.
+func mergeMaps(dst, src map[string]interface{}, strategy MergeStrategy, key string) map[string]interface{} {
+	if dst == nil {
+		dst = make(map[string]interface{})
+	}
+	for k, sv := range src {
+		dv, exists := dst[k]
+		if !exists {
+			dst[k] = sv
+			continue
+		}
+		dst[k] = mergeValues(dv, sv, strategy, key)
+	}
+	return dst
+}
+
+// mergeValues merges a single src value into dst according to strategy,
+// recursing into nested maps and slices of maps.
+func mergeValues(dst, src interface{}, strategy MergeStrategy, key string) interface{} {
+	switch dstV := dst.(type) {
+	case map[string]interface{}:
+		if srcV, ok := src.(map[string]interface{}); ok {
+			return mergeMaps(dstV, srcV, strategy, key)
+		}
+		return dst
+	case []interface{}:
+		if srcV, ok := src.([]interface{}); ok {
+			return mergeSlices(dstV, srcV, strategy, key)
+		}
+		return dst
+	default:
+		// dst already has a value set for this key, so it wins over the
+		// imported value, consistent with the loader's existing precedence.
+		return dst
+	}
+}
+
+// mergeSlices combines a base and imported slice according to strategy.
+func mergeSlices(dst, src []interface{}, strategy MergeStrategy, key string) []interface{} {
+	switch strategy {
+	case MergeReplace:
+		return src
+	case MergePrepend:
+		out := make([]interface{}, 0, len(dst)+len(src))
+		out = append(out, src...)
+		out = append(out, dst...)
+		return out
+	case MergeUniqueByKey:
+		return mergeSlicesUniqueByKey(dst, src, key)
+	case MergeAppend:
+		fallthrough
+	default:
+		out := make([]interface{}, 0, len(dst)+len(src))
+		out = append(out, dst...)
+		out = append(out, src...)
+		return out
+	}
+}
+
+// mergeSlicesUniqueByKey merges two slices of maps, matching elements whose
+// key field is equal: matched elements are deep-merged, and unmatched src
+// elements are appended. Elements that are not maps, or lack key, are always
+// appended, since they cannot be matched.
+//
+// key only scopes the top-level match: fields nested inside a matched
+// element are merged with MergeAppend, since a slice of maps nested deeper
+// in the tree may use its own, differently-named key field (or none at
+// all), and reusing the outer key there would either misfire or silently
+// drop elements.
+func mergeSlicesUniqueByKey(dst, src []interface{}, key string) []interface{} {
+	out := append([]interface{}{}, dst...)
+	index := make(map[interface{}]int, len(out))
+	if key != "" {
+		for i, item := range out {
+			if m, ok := item.(map[string]interface{}); ok {
+				if kv, ok := m[key]; ok {
+					index[kv] = i
+				}
+			}
+		}
+	}
+	for _, item := range src {
+		m, ok := item.(map[string]interface{})
+		if !ok || key == "" {
+			out = append(out, item)
+			continue
+		}
+		kv, ok := m[key]
+		if !ok {
+			out = append(out, item)
+			continue
+		}
+		if i, ok := index[kv]; ok {
+			out[i] = mergeMaps(out[i].(map[string]interface{}), m, MergeAppend, "")
+			continue
+		}
+		index[kv] = len(out)
+		out = append(out, item)
+	}
+	return out
+}
+
+// applyLocalOverride looks for a sibling "<path>.local" file and, if present,
+// deep-merges its contents on top of root. Scalars and maps in the override
+// take precedence over root; slices replace root's slices unless
+// opts.appendLocalSlices is set, in which case they are appended instead.
+// A missing override file is not an error.
+func applyLocalOverride(path string, root map[string]interface{}, opts *loadOptions, provenance map[string][]string) error {
+	localPath := path + ".local"
+	b, err := ioutil.ReadFile(localPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read local override file %q: %v", localPath, err)
+	}
+
+	var raw json.RawMessage
+	if err := yaml.Unmarshal(b, &raw); err != nil {
+		return fmt.Errorf("failed to unmarshal local override at path %q: %v", localPath, err)
+	}
+	override := make(map[string]interface{})
+	if err := json.Unmarshal(raw, &override); err != nil {
+		return fmt.Errorf("failed to unmarshal raw local override to map at path %q: %v", localPath, err)
+	}
+	recordProvenance(provenance, "", override, localPath)
+
+	strategy := MergeReplace
+	if opts != nil && opts.appendLocalSlices {
+		strategy = MergeAppend
+	}
+	overrideInto(root, override, strategy)
+	return nil
+}
+
+// overrideInto deep-merges override into root in place, with override's
+// values winning over root's wherever they conflict. This is the mirror
+// image of mergeMaps' "base wins over imports" precedence: an override file
+// exists to override what it's layered onto, not to fall back to it, so it
+// cannot share mergeMaps' dst-wins convention directly.
+func overrideInto(root, override map[string]interface{}, strategy MergeStrategy) {
+	for k, ov := range override {
+		rv, exists := root[k]
+		if !exists {
+			root[k] = ov
+			continue
+		}
+		if ovMap, ok := ov.(map[string]interface{}); ok {
+			if rvMap, ok := rv.(map[string]interface{}); ok {
+				overrideInto(rvMap, ovMap, strategy)
+				continue
+			}
+			root[k] = ov
+			continue
+		}
+		if ovSlice, ok := ov.([]interface{}); ok {
+			if rvSlice, ok := rv.([]interface{}); ok && strategy == MergeAppend {
+				out := make([]interface{}, 0, len(rvSlice)+len(ovSlice))
+				out = append(out, rvSlice...)
+				out = append(out, ovSlice...)
+				root[k] = out
+				continue
+			}
+			root[k] = ov
+			continue
+		}
+		root[k] = ov
+	}
+}
+
+// patternMatch is a single file resolved from a pattern/recursive importsItem,
+// paired with the entry that produced it so its Strategy/Key are not lost.
+type patternMatch struct {
+	path string
+	imp  *importsItem
+}
+
+// patternPaths returns all files matching the patterns and recursive imports
+// defined in importsList, in lexical order so that merging is deterministic
+// even when two matched files touch the same key. Each match carries the
+// importsItem that produced it, so callers can honor its Strategy/Key instead
+// of always falling back to the loader's default.
 // If projectYAMLPath match patterns, the result always ignore it.
-// projectYAMLPath should be an absolute path.
-// Patterns in importsList could be relative path to the projectYAMLPath
-// or absolute paths.
+// dir is the directory relative patterns are resolved against; it is
+// typically the directory of projectYAMLPath, but is passed explicitly so
+// patternPaths also works for configs loaded via LoadReader, which have no
+// projectYAMLPath of their own.
+// Patterns in importsList could be relative to dir or absolute paths.
 // For example, if "./*.yaml" is an entry of "imports", the project YAML itself
 // would match the pattern. We should exclude that path because we do not want to
 // include the content of that YAML twice.
-func patternPaths(projectYAMLPath string, importsList []*importsItem) ([]string, error) {
-	allMatches := make(map[string]bool)
-	projectYamlFolder := filepath.Dir(projectYAMLPath)
+func patternPaths(dir, projectYAMLPath string, importsList []*importsItem) ([]patternMatch, error) {
+	owner := make(map[string]*importsItem)
 	for _, importItem := range importsList {
-		// joinedPath would be always an absolute path (pattern).
-		joinedPath := importItem.Pattern
-		if joinedPath == "" {
-			continue
-		}
-		if len(importItem.Data) > 0 {
-			return nil, fmt.Errorf("import cannot have both pattern and data set together")
-		}
-		if !filepath.IsAbs(joinedPath) {
-			joinedPath = filepath.Join(projectYamlFolder, importItem.Pattern)
-		}
-		matches, err := filepath.Glob(joinedPath)
-		if err != nil {
-			return nil, fmt.Errorf("pattern %q is malformed", importItem.Pattern)
+		var matches []string
+		var err error
+		if importItem.Recursive {
+			matches, err = recursiveImportPaths(dir, importItem)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			joinedPath := importItem.Pattern
+			if joinedPath == "" {
+				continue
+			}
+			if len(importItem.Data) > 0 {
+				return nil, fmt.Errorf("import cannot have both pattern and data set together")
+			}
+			if !filepath.IsAbs(joinedPath) {
+				joinedPath = filepath.Join(dir, importItem.Pattern)
+			}
+			matches, err = filepath.Glob(joinedPath)
+			if err != nil {
+				return nil, fmt.Errorf("pattern %q is malformed", importItem.Pattern)
+			}
 		}
 		for _, match := range matches {
 			if match == projectYAMLPath {
 				continue
 			}
-			allMatches[match] = true
+			if _, ok := owner[match]; !ok {
+				owner[match] = importItem
+			}
 		}
 	}
-	var filePathList []string
-	for path := range allMatches {
+	filePathList := make([]string, 0, len(owner))
+	for path := range owner {
 		filePathList = append(filePathList, path)
 	}
-	return filePathList, nil
+	sort.Strings(filePathList)
+	out := make([]patternMatch, len(filePathList))
+	for i, path := range filePathList {
+		out[i] = patternMatch{path: path, imp: owner[path]}
+	}
+	return out, nil
+}
+
+// recursiveImportPaths resolves a `recursive: true` import entry into every
+// *.yaml/*.yml/*.json file under its directory tree: `path: conf.d` walks
+// conf.d entirely, while a Pattern containing a "**" doublestar segment (e.g.
+// `pattern: conf.d/**/*.yaml`) additionally filters matches by the segment
+// following the "**".
+func recursiveImportPaths(dir string, importItem *importsItem) ([]string, error) {
+	root := importItem.Pattern
+	if root == "" {
+		root = importItem.Path
+	}
+	if root == "" {
+		return nil, fmt.Errorf("recursive import must set path or pattern")
+	}
+	if !filepath.IsAbs(root) {
+		root = filepath.Join(dir, root)
+	}
+
+	suffix := ""
+	if idx := strings.Index(root, "**"); idx != -1 {
+		suffix = strings.TrimPrefix(root[idx+len("**"):], string(filepath.Separator))
+		root = filepath.Dir(root[:idx])
+	}
+
+	var matches []string
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		if !isConfigExt(p) {
+			return nil
+		}
+		if suffix != "" {
+			ok, err := filepath.Match(suffix, filepath.Base(p))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return nil
+			}
+		}
+		matches = append(matches, p)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk recursive import %q: %v", root, err)
+	}
+	return matches, nil
+}
+
+// isConfigExt reports whether p has a file extension the loader understands.
+func isConfigExt(p string) bool {
+	switch strings.ToLower(filepath.Ext(p)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
 }
\ No newline at end of file
@@ -0,0 +1,570 @@
+/*
+ * Copyright 2019 Google LLC.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergeMaps(t *testing.T) {
+	dst := map[string]interface{}{
+		"a": "dst-wins",
+		"b": map[string]interface{}{
+			"x": "dst-wins",
+		},
+	}
+	src := map[string]interface{}{
+		"a": "src-loses",
+		"b": map[string]interface{}{
+			"x": "src-loses",
+			"y": "src-only",
+		},
+		"c": "src-only",
+	}
+	got := mergeMaps(dst, src, MergeAppend, "")
+	want := map[string]interface{}{
+		"a": "dst-wins",
+		"b": map[string]interface{}{
+			"x": "dst-wins",
+			"y": "src-only",
+		},
+		"c": "src-only",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeMaps(dst, src) = %v, want %v", got, want)
+	}
+}
+
+func TestMergeSlices(t *testing.T) {
+	dst := []interface{}{"a", "b"}
+	src := []interface{}{"c", "d"}
+
+	tests := []struct {
+		strategy MergeStrategy
+		want     []interface{}
+	}{
+		{MergeAppend, []interface{}{"a", "b", "c", "d"}},
+		{MergeReplace, []interface{}{"c", "d"}},
+		{MergePrepend, []interface{}{"c", "d", "a", "b"}},
+	}
+	for _, tc := range tests {
+		got := mergeSlices(dst, src, tc.strategy, "")
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("mergeSlices(%v, %v, %v) = %v, want %v", dst, src, tc.strategy, got, tc.want)
+		}
+	}
+}
+
+func TestMergeSlicesUniqueByKey(t *testing.T) {
+	dst := []interface{}{
+		map[string]interface{}{"name": "a", "value": 1.0},
+		"not-a-map",
+	}
+	src := []interface{}{
+		map[string]interface{}{"name": "a", "extra": "added"},
+		map[string]interface{}{"name": "b", "value": 2.0},
+		map[string]interface{}{"value": "no-key-field"},
+	}
+
+	got := mergeSlicesUniqueByKey(dst, src, "name")
+	want := []interface{}{
+		map[string]interface{}{"name": "a", "value": 1.0, "extra": "added"},
+		"not-a-map",
+		map[string]interface{}{"name": "b", "value": 2.0},
+		map[string]interface{}{"value": "no-key-field"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeSlicesUniqueByKey() = %v, want %v", got, want)
+	}
+}
+
+// TestMergeSlicesUniqueByKeyNestedKeyScoping is a regression test: a nested
+// slice of maps keyed by a different field than the outer key must not be
+// deduplicated by the outer key, and must not lose elements.
+func TestMergeSlicesUniqueByKeyNestedKeyScoping(t *testing.T) {
+	dst := []interface{}{
+		map[string]interface{}{
+			"name": "a",
+			"ports": []interface{}{
+				map[string]interface{}{"port": 80.0},
+			},
+		},
+	}
+	src := []interface{}{
+		map[string]interface{}{
+			"name": "a",
+			"ports": []interface{}{
+				map[string]interface{}{"port": 443.0},
+			},
+		},
+	}
+
+	got := mergeSlicesUniqueByKey(dst, src, "name")
+	want := []interface{}{
+		map[string]interface{}{
+			"name": "a",
+			"ports": []interface{}{
+				map[string]interface{}{"port": 80.0},
+				map[string]interface{}{"port": 443.0},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeSlicesUniqueByKey() = %v, want %v", got, want)
+	}
+}
+
+func TestCloneMapIsIndependent(t *testing.T) {
+	orig := map[string]interface{}{
+		"a": map[string]interface{}{"b": "c"},
+		"d": []interface{}{"e", "f"},
+	}
+	clone := cloneMap(orig)
+	clone["a"].(map[string]interface{})["b"] = "mutated"
+	clone["d"].([]interface{})[0] = "mutated"
+
+	if orig["a"].(map[string]interface{})["b"] != "c" {
+		t.Errorf("mutating clone affected original map: %v", orig)
+	}
+	if orig["d"].([]interface{})[0] != "e" {
+		t.Errorf("mutating clone affected original slice: %v", orig)
+	}
+}
+
+func TestPatternPaths(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.yaml", "a.yaml", "c.yaml"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	projectPath := filepath.Join(dir, "a.yaml")
+
+	imp := &importsItem{Pattern: "*.yaml"}
+	got, err := patternPaths(dir, projectPath, []*importsItem{imp})
+	if err != nil {
+		t.Fatalf("patternPaths() error: %v", err)
+	}
+	want := []patternMatch{
+		{path: filepath.Join(dir, "b.yaml"), imp: imp},
+		{path: filepath.Join(dir, "c.yaml"), imp: imp},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("patternPaths() = %v, want %v", got, want)
+	}
+}
+
+// TestPatternPathsHonorsPerImportStrategy is a regression test: a matched
+// file's Strategy/Key must come from the importsItem that produced the
+// match, not be discarded in favor of the loader's default.
+func TestPatternPathsHonorsPerImportStrategy(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.yaml"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	imp := &importsItem{Pattern: "a.yaml", Strategy: MergeReplace, Key: "name"}
+	got, err := patternPaths(dir, "", []*importsItem{imp})
+	if err != nil {
+		t.Fatalf("patternPaths() error: %v", err)
+	}
+	if len(got) != 1 || got[0].imp != imp {
+		t.Fatalf("patternPaths() = %v, want a single match owned by %v", got, imp)
+	}
+}
+
+func TestRecursiveImportPaths(t *testing.T) {
+	dir := t.TempDir()
+	mustWrite := func(rel string) {
+		p := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustWrite("conf.d/a.yaml")
+	mustWrite("conf.d/nested/b.yaml")
+	mustWrite("conf.d/nested/c.txt")
+
+	got, err := recursiveImportPaths(dir, &importsItem{Path: "conf.d"})
+	if err != nil {
+		t.Fatalf("recursiveImportPaths() error: %v", err)
+	}
+	want := []string{
+		filepath.Join(dir, "conf.d/a.yaml"),
+		filepath.Join(dir, "conf.d/nested/b.yaml"),
+	}
+	sortedEqual(t, got, want)
+}
+
+func TestRecursiveImportPathsDoublestarSuffix(t *testing.T) {
+	dir := t.TempDir()
+	mustWrite := func(rel string) {
+		p := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustWrite("conf.d/a.yaml")
+	mustWrite("conf.d/nested/b.yaml")
+	mustWrite("conf.d/nested/b.json")
+
+	got, err := recursiveImportPaths(dir, &importsItem{Pattern: "conf.d/**/*.yaml"})
+	if err != nil {
+		t.Fatalf("recursiveImportPaths() error: %v", err)
+	}
+	// "**" matches zero or more directories, so a.yaml directly under conf.d
+	// is a match too, not just nested/b.yaml.
+	want := []string{
+		filepath.Join(dir, "conf.d/a.yaml"),
+		filepath.Join(dir, "conf.d/nested/b.yaml"),
+	}
+	sortedEqual(t, got, want)
+}
+
+func sortedEqual(t *testing.T, got, want []string) {
+	t.Helper()
+	gotCopy := append([]string(nil), got...)
+	wantCopy := append([]string(nil), want...)
+	if len(gotCopy) != len(wantCopy) {
+		t.Errorf("got %v, want %v", got, want)
+		return
+	}
+	gotSet := make(map[string]bool, len(gotCopy))
+	for _, g := range gotCopy {
+		gotSet[g] = true
+	}
+	for _, w := range wantCopy {
+		if !gotSet[w] {
+			t.Errorf("got %v, want %v (missing %q)", got, want, w)
+		}
+	}
+}
+
+func TestLoadMapCycleDetection(t *testing.T) {
+	dir := t.TempDir()
+	aPath := filepath.Join(dir, "a.yaml")
+	bPath := filepath.Join(dir, "b.yaml")
+	if err := ioutil.WriteFile(aPath, []byte(fmt.Sprintf("imports:\n- path: %q\n", bPath)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(bPath, []byte(fmt.Sprintf("imports:\n- path: %q\n", aPath)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	st := newLoadState(new(loadOptions))
+	if _, err := loadMap(aPath, nil, st); err == nil {
+		t.Fatal("loadMap() with an import cycle returned no error")
+	}
+}
+
+func TestLoadMapDiamondImport(t *testing.T) {
+	dir := t.TempDir()
+	dPath := filepath.Join(dir, "d.yaml")
+	bPath := filepath.Join(dir, "b.yaml")
+	cPath := filepath.Join(dir, "c.yaml")
+	aPath := filepath.Join(dir, "a.yaml")
+
+	if err := ioutil.WriteFile(dPath, []byte("shared: from-d\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(bPath, []byte(fmt.Sprintf("imports:\n- path: %q\n", dPath)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(cPath, []byte(fmt.Sprintf("imports:\n- path: %q\n", dPath)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(aPath, []byte(fmt.Sprintf("imports:\n- path: %q\n- path: %q\n", bPath, cPath)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	st := newLoadState(new(loadOptions))
+	got, err := loadMap(aPath, nil, st)
+	if err != nil {
+		t.Fatalf("loadMap() error: %v", err)
+	}
+	if got["shared"] != "from-d" {
+		t.Errorf("loadMap()[\"shared\"] = %v, want %q", got["shared"], "from-d")
+	}
+	if _, ok := st.cache[dPath]; !ok {
+		t.Errorf("expected %q to be cached after being imported via two paths", dPath)
+	}
+}
+
+func TestApplyLocalOverride(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "conf.yaml")
+	localPath := path + ".local"
+	if err := ioutil.WriteFile(localPath, []byte("a: override\nslice: [\"z\"]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	root := map[string]interface{}{
+		"a":     "base",
+		"b":     "base-only",
+		"slice": []interface{}{"x", "y"},
+	}
+	provenance := make(map[string][]string)
+	if err := applyLocalOverride(path, root, new(loadOptions), provenance); err != nil {
+		t.Fatalf("applyLocalOverride() error: %v", err)
+	}
+	if root["a"] != "override" {
+		t.Errorf("root[\"a\"] = %v, want %q", root["a"], "override")
+	}
+	if root["b"] != "base-only" {
+		t.Errorf("root[\"b\"] = %v, want %q", root["b"], "base-only")
+	}
+	if got, want := root["slice"], []interface{}{"z"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("root[\"slice\"] = %v, want %v (replace by default)", got, want)
+	}
+	if len(provenance["a"]) == 0 || provenance["a"][len(provenance["a"])-1] != localPath {
+		t.Errorf("provenance[\"a\"] = %v, want it to end with %q", provenance["a"], localPath)
+	}
+}
+
+func TestApplyLocalOverrideAppendSlice(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "conf.yaml")
+	localPath := path + ".local"
+	if err := ioutil.WriteFile(localPath, []byte("slice: [\"z\"]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	root := map[string]interface{}{
+		"slice": []interface{}{"x", "y"},
+	}
+	opts := &loadOptions{appendLocalSlices: true}
+	if err := applyLocalOverride(path, root, opts, make(map[string][]string)); err != nil {
+		t.Fatalf("applyLocalOverride() error: %v", err)
+	}
+	want := []interface{}{"x", "y", "z"}
+	if got := root["slice"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("root[\"slice\"] = %v, want %v", got, want)
+	}
+}
+
+func TestApplyLocalOverrideMissingFileIsNotAnError(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "conf.yaml")
+	root := map[string]interface{}{"a": "base"}
+	if err := applyLocalOverride(path, root, new(loadOptions), make(map[string][]string)); err != nil {
+		t.Fatalf("applyLocalOverride() with no .local file returned error: %v", err)
+	}
+	if root["a"] != "base" {
+		t.Errorf("root was mutated despite no .local file: %v", root)
+	}
+}
+
+func TestCorrelateProvenance(t *testing.T) {
+	provenance := map[string][]string{
+		"id": {"base.yaml"},
+	}
+
+	tests := []struct {
+		name      string
+		err       error
+		wantMatch bool
+	}{
+		{"whole word match", errors.New(`field "id" is required`), true},
+		{"substring inside unrelated word", errors.New("invalid configuration"), false},
+	}
+	for _, tc := range tests {
+		got := correlateProvenance(tc.err, provenance)
+		matched := got.Error() != tc.err.Error()
+		if matched != tc.wantMatch {
+			t.Errorf("%s: correlateProvenance(%v) = %q, wantMatch %v", tc.name, tc.err, got, tc.wantMatch)
+		}
+	}
+}
+
+func TestCorrelateProvenanceDeterministic(t *testing.T) {
+	provenance := map[string][]string{
+		"id":   {"a.yaml"},
+		"name": {"b.yaml"},
+	}
+	err := errors.New("id and name are both invalid")
+	var first string
+	for i := 0; i < 20; i++ {
+		got := correlateProvenance(err, provenance).Error()
+		if first == "" {
+			first = got
+		} else if got != first {
+			t.Fatalf("correlateProvenance() is non-deterministic: got %q, previously %q", got, first)
+		}
+	}
+}
+
+func TestMultiErrorFlattensNested(t *testing.T) {
+	inner := &MultiError{Errs: []error{errors.New("a"), errors.New("b")}}
+	outer := new(MultiError)
+	outer.add(inner)
+	outer.add(errors.New("c"))
+	outer.add(nil)
+
+	if len(outer.Errs) != 3 {
+		t.Fatalf("outer.Errs = %v, want 3 flattened errors", outer.Errs)
+	}
+	if outer.errOrNil() == nil {
+		t.Error("errOrNil() = nil, want non-nil for a MultiError with errors")
+	}
+	if (new(MultiError)).errOrNil() != nil {
+		t.Error("errOrNil() on an empty MultiError should be nil")
+	}
+}
+
+func TestNewImportErrorYAMLLine(t *testing.T) {
+	err := newImportError("a.yaml", []byte("a: b\nc: d"), fmt.Errorf("yaml: line 2: did not find expected key"))
+	if err.Line != 2 {
+		t.Errorf("newImportError().Line = %d, want 2", err.Line)
+	}
+}
+
+func TestNewImportErrorJSONSyntaxError(t *testing.T) {
+	raw := []byte("{\n  \"a\": ,\n}")
+	var v interface{}
+	jsonErr := json.Unmarshal(raw, &v)
+	if jsonErr == nil {
+		t.Fatal("expected json.Unmarshal to fail on malformed input")
+	}
+	synErr, ok := jsonErr.(*json.SyntaxError)
+	if !ok {
+		t.Fatalf("expected *json.SyntaxError, got %T: %v", jsonErr, jsonErr)
+	}
+	err := newImportError("a.json", raw, synErr)
+	if err.Line != 2 {
+		t.Errorf("newImportError().Line = %d, want 2", err.Line)
+	}
+}
+
+func TestRecordProvenance(t *testing.T) {
+	provenance := make(map[string][]string)
+	m := map[string]interface{}{
+		"projects": map[string]interface{}{
+			"id": "my-project",
+		},
+		"name": "top-level",
+	}
+	recordProvenance(provenance, "", m, "a.yaml")
+	recordProvenance(provenance, "", m, "b.yaml")
+
+	if got, want := provenance["projects.id"], []string{"a.yaml", "b.yaml"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("provenance[\"projects.id\"] = %v, want %v", got, want)
+	}
+	if got, want := provenance["name"], []string{"a.yaml", "b.yaml"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("provenance[\"name\"] = %v, want %v", got, want)
+	}
+}
+
+// TestLoadMapPatternImportHonorsStrategy is a regression test: a
+// pattern:-based importsItem's own Strategy/Key must be honored, not
+// silently replaced by the loader's default.
+func TestLoadMapPatternImportHonorsStrategy(t *testing.T) {
+	dir := t.TempDir()
+	projectPath := filepath.Join(dir, "project.yaml")
+	if err := ioutil.WriteFile(projectPath, []byte("bar: [\"r\"]\nimports:\n- pattern: a.yaml\n  strategy: replace\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.yaml"), []byte("bar: [\"x\", \"y\"]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	st := newLoadState(new(loadOptions))
+	got, err := loadMap(projectPath, nil, st)
+	if err != nil {
+		t.Fatalf("loadMap() error: %v", err)
+	}
+	want := []interface{}{"x", "y"}
+	if !reflect.DeepEqual(got["bar"], want) {
+		t.Errorf("loadMap()[\"bar\"] = %v, want %v (pattern import's own strategy: replace should apply)", got["bar"], want)
+	}
+}
+
+// TestLoadEndToEnd exercises the public Load entry point against a temp-dir
+// project file with both a regular import and a .local override, verifying
+// the whole tree was actually read and merged via the provenance it leaves
+// behind.
+func TestLoadEndToEnd(t *testing.T) {
+	dir := t.TempDir()
+	projectPath := filepath.Join(dir, "project.yaml")
+	importedPath := filepath.Join(dir, "imported.yaml")
+	localPath := projectPath + ".local"
+
+	if err := ioutil.WriteFile(projectPath, []byte("shared: base\nimports:\n- path: imported.yaml\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(importedPath, []byte("other: from-import\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(localPath, []byte("shared: overridden\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	conf, err := Load(projectPath)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if got, want := conf.Provenance["shared"], []string{projectPath, localPath}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Provenance[\"shared\"] = %v, want %v", got, want)
+	}
+	if got, want := conf.Provenance["other"], []string{importedPath}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Provenance[\"other\"] = %v, want %v", got, want)
+	}
+}
+
+// TestLoadReaderEndToEnd exercises the public LoadReader entry point, which
+// has no file of its own to record provenance against.
+func TestLoadReaderEndToEnd(t *testing.T) {
+	conf, err := LoadReader(strings.NewReader("foo: bar\n"), "")
+	if err != nil {
+		t.Fatalf("LoadReader() error: %v", err)
+	}
+	if got, want := conf.Provenance["foo"], []string{"<reader>"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Provenance[\"foo\"] = %v, want %v", got, want)
+	}
+}
+
+// TestMergeEndToEnd exercises the public (*Config).Merge entry point,
+// confirming it layers new content on top of an existing Config without
+// losing the provenance already recorded on it.
+func TestMergeEndToEnd(t *testing.T) {
+	conf, err := LoadReader(strings.NewReader("foo: bar\n"), "")
+	if err != nil {
+		t.Fatalf("LoadReader() error: %v", err)
+	}
+	if err := conf.Merge(strings.NewReader("baz: qux\n")); err != nil {
+		t.Fatalf("Merge() error: %v", err)
+	}
+	if _, ok := conf.Provenance["foo"]; !ok {
+		t.Error("Merge() dropped provenance recorded before it was called")
+	}
+	if _, ok := conf.Provenance["baz"]; !ok {
+		t.Error("Merge() did not record provenance for the content it merged in")
+	}
+}